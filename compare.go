@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+
+	"golang.org/x/perf/benchstat"
+)
+
+// collection builds a benchstat.Collection from two benchmark runs, each
+// labelled "#ID (commit)", ready to be rendered as a text table or HTML.
+func collection(a, b Benchmark) *benchstat.Collection {
+	c := &benchstat.Collection{
+		Alpha:      0.05,
+		AddGeoMean: false,
+	}
+	c.AddConfig(fmt.Sprintf("#%d (%s)", a.ID, a.Commit), []byte(a.Content))
+	c.AddConfig(fmt.Sprintf("#%d (%s)", b.ID, b.Commit), []byte(b.Content))
+	return c
+}
+
+// CompareText renders the benchstat delta between two benchmark runs as a
+// plain-text table, the same output `benchstat` prints on the command
+// line.
+func CompareText(a, b Benchmark) ([]byte, error) {
+	tables := collection(a, b).Tables()
+
+	var buf bytes.Buffer
+	benchstat.FormatText(&buf, tables)
+	return buf.Bytes(), nil
+}
+
+// CompareHTML renders the benchstat delta between two benchmark runs as
+// an HTML fragment suitable for embedding in the compare page.
+func CompareHTML(a, b Benchmark) ([]byte, error) {
+	tables := collection(a, b).Tables()
+
+	var buf bytes.Buffer
+	if err := compareTmpl.Execute(&buf, tables); err != nil {
+		return nil, fmt.Errorf("render compare table: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// pairSetRuns splits a benchmark set's runs into pairs to compare. Runs
+// labelled "old" and "new" are paired up positionally; otherwise
+// consecutive runs are paired in upload order.
+func pairSetRuns(set BenchmarkSet) ([][2]Benchmark, error) {
+	if len(set.Runs) < 2 {
+		return nil, fmt.Errorf("benchmark set %d has fewer than 2 runs", set.ID)
+	}
+
+	var olds, news []Benchmark
+	for _, run := range set.Runs {
+		switch run.Label {
+		case "old":
+			olds = append(olds, run)
+		case "new":
+			news = append(news, run)
+		}
+	}
+	if len(olds) > 0 && len(olds) == len(news) {
+		pairs := make([][2]Benchmark, len(olds))
+		for i := range olds {
+			pairs[i] = [2]Benchmark{olds[i], news[i]}
+		}
+		return pairs, nil
+	}
+
+	if len(set.Runs)%2 != 0 {
+		return nil, fmt.Errorf("benchmark set %d has an odd number of runs and no old/new labels to pair by", set.ID)
+	}
+	pairs := make([][2]Benchmark, 0, len(set.Runs)/2)
+	for i := 0; i+1 < len(set.Runs); i += 2 {
+		pairs = append(pairs, [2]Benchmark{set.Runs[i], set.Runs[i+1]})
+	}
+	return pairs, nil
+}
+
+// runLabel returns a run's label, falling back to its ID when it wasn't
+// uploaded with one.
+func runLabel(b Benchmark) string {
+	if b.Label != "" {
+		return b.Label
+	}
+	return fmt.Sprintf("#%d", b.ID)
+}
+
+// CompareSetText renders the pairwise benchstat deltas within set as a
+// plain-text table per pair.
+func CompareSetText(set BenchmarkSet) ([]byte, error) {
+	pairs, err := pairSetRuns(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, pair := range pairs {
+		fmt.Fprintf(&buf, "%s vs %s\n", runLabel(pair[0]), runLabel(pair[1]))
+		cmp, err := CompareText(pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(cmp)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// CompareSetHTML renders the pairwise benchstat deltas within set as an
+// HTML fragment, one table per pair.
+func CompareSetHTML(set BenchmarkSet) ([]byte, error) {
+	pairs, err := pairSetRuns(set)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	for _, pair := range pairs {
+		fmt.Fprintf(&buf, "<h3>%s vs %s</h3>", template.HTMLEscapeString(runLabel(pair[0])), template.HTMLEscapeString(runLabel(pair[1])))
+		cmp, err := CompareHTML(pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(cmp)
+	}
+	return buf.Bytes(), nil
+}
+
+var compareTmpl = template.Must(template.New("compare").Parse(`
+{{range .}}
+	<h2>{{.Metric}}</h2>
+	<table>
+		<thead>
+			<tr>
+				<td>benchmark</td>
+				{{range .Configs}}<td>{{.}}</td>{{end}}
+			</tr>
+		</thead>
+		<tbody>
+			{{range $row := .Rows}}
+				<tr>
+					<td>{{$row.Benchmark}}</td>
+					{{range $row.Metrics}}<td>{{.Format $row.Scaler}}</td>{{end}}
+				</tr>
+			{{end}}
+		</tbody>
+	</table>
+{{end}}
+`))
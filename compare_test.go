@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newCompareStore() *memStore {
+	s := newMemStore()
+	s.benchmarks = []Benchmark{
+		{ID: 1, Commit: "abc123", Content: "BenchmarkFoo-8   1000000   100 ns/op\n", Created: time.Now()},
+		{ID: 2, Commit: "def456", Content: "BenchmarkFoo-8   1000000   120 ns/op\n", Created: time.Now()},
+	}
+	s.nextID = 3
+	return s
+}
+
+func TestCompareHandlerHTML(t *testing.T) {
+	h := compareHandler(newCompareStore())
+
+	req := httptest.NewRequest(http.MethodGet, "/compare/?a=1&b=2", nil)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Body.String(); !containsTag(got) {
+		t.Fatalf("expected an HTML table, got %q", got)
+	}
+}
+
+func TestCompareHandlerText(t *testing.T) {
+	h := compareHandler(newCompareStore())
+
+	req := httptest.NewRequest(http.MethodGet, "/compare/?a=1&b=2", nil)
+	req.Header.Set("Accept", "text/plain")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("content-type"); ct != "text/plain;charset=utf-8" {
+		t.Fatalf("content-type = %q", ct)
+	}
+	if containsTag(rec.Body.String()) {
+		t.Fatalf("expected a plain text table, got HTML: %q", rec.Body.String())
+	}
+}
+
+func TestCompareHandlerTextViaBenchsaveHeader(t *testing.T) {
+	h := compareHandler(newCompareStore())
+
+	req := httptest.NewRequest(http.MethodGet, "/compare/?a=1&b=2", nil)
+	req.Header.Set("X-Benchsave", "1")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if containsTag(rec.Body.String()) {
+		t.Fatalf("expected a plain text table, got HTML: %q", rec.Body.String())
+	}
+}
+
+func newSetStore(t *testing.T, runs ...Benchmark) (*memStore, int64) {
+	t.Helper()
+
+	s := newMemStore()
+	set := BenchmarkSet{ID: 1, Commit: "abc123", Runs: runs}
+	s.sets = append(s.sets, set)
+	s.nextSetID = 2
+	return s, set.ID
+}
+
+func TestPairSetRunsOldNewLabels(t *testing.T) {
+	store, setID := newSetStore(t,
+		Benchmark{ID: 1, Label: "old", Content: "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		Benchmark{ID: 2, Label: "new", Content: "BenchmarkFoo-8   1000000   120 ns/op\n"},
+	)
+	set, err := store.FindBenchmarkSet(context.Background(), setID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, err := pairSetRuns(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 1 || pairs[0][0].Label != "old" || pairs[0][1].Label != "new" {
+		t.Fatalf("unexpected pairs: %+v", pairs)
+	}
+}
+
+func TestPairSetRunsPositionalFallback(t *testing.T) {
+	store, setID := newSetStore(t,
+		Benchmark{ID: 1, Content: "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		Benchmark{ID: 2, Content: "BenchmarkFoo-8   1000000   120 ns/op\n"},
+		Benchmark{ID: 3, Content: "BenchmarkFoo-8   1000000   90 ns/op\n"},
+		Benchmark{ID: 4, Content: "BenchmarkFoo-8   1000000   95 ns/op\n"},
+	)
+	set, err := store.FindBenchmarkSet(context.Background(), setID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs, err := pairSetRuns(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pairs) != 2 || pairs[0][0].ID != 1 || pairs[0][1].ID != 2 || pairs[1][0].ID != 3 || pairs[1][1].ID != 4 {
+		t.Fatalf("unexpected pairs: %+v", pairs)
+	}
+}
+
+func TestPairSetRunsTooFewRuns(t *testing.T) {
+	store, setID := newSetStore(t, Benchmark{ID: 1, Content: "BenchmarkFoo-8   1000000   100 ns/op\n"})
+	set, err := store.FindBenchmarkSet(context.Background(), setID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pairSetRuns(set); err == nil {
+		t.Fatal("expected an error for a set with fewer than 2 runs")
+	}
+}
+
+func TestPairSetRunsOddUnbalancedCount(t *testing.T) {
+	store, setID := newSetStore(t,
+		Benchmark{ID: 1, Content: "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		Benchmark{ID: 2, Content: "BenchmarkFoo-8   1000000   120 ns/op\n"},
+		Benchmark{ID: 3, Content: "BenchmarkFoo-8   1000000   90 ns/op\n"},
+	)
+	set, err := store.FindBenchmarkSet(context.Background(), setID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := pairSetRuns(set); err == nil {
+		t.Fatal("expected an error for an odd, unbalanced number of runs")
+	}
+}
+
+func TestCompareSetTextAndHTML(t *testing.T) {
+	store, setID := newSetStore(t,
+		Benchmark{ID: 1, Label: "old", Content: "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		Benchmark{ID: 2, Label: "new", Content: "BenchmarkFoo-8   1000000   120 ns/op\n"},
+	)
+	set, err := store.FindBenchmarkSet(context.Background(), setID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text, err := CompareSetText(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(text), "old vs new") {
+		t.Fatalf("expected run labels in the text output, got %q", text)
+	}
+
+	html, err := CompareSetHTML(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !containsTag(string(html)) {
+		t.Fatalf("expected an HTML fragment, got %q", html)
+	}
+}
+
+func TestCompareHandlerSetLabelled(t *testing.T) {
+	store, setID := newSetStore(t,
+		Benchmark{ID: 1, Label: "old", Content: "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		Benchmark{ID: 2, Label: "new", Content: "BenchmarkFoo-8   1000000   120 ns/op\n"},
+	)
+	h := compareHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/compare/?set=%d", setID), nil)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !containsTag(rec.Body.String()) {
+		t.Fatalf("expected an HTML table, got %q", rec.Body.String())
+	}
+}
+
+func TestCompareHandlerSetUnlabelled(t *testing.T) {
+	store, setID := newSetStore(t,
+		Benchmark{ID: 1, Content: "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		Benchmark{ID: 2, Content: "BenchmarkFoo-8   1000000   120 ns/op\n"},
+	)
+	h := compareHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/compare/?set=%d", setID), nil)
+	req.Header.Set("Accept", "text/plain")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "#1 vs #2") {
+		t.Fatalf("expected ID-based run labels, got %q", rec.Body.String())
+	}
+}
+
+func containsTag(s string) bool {
+	for i := range s {
+		if s[i] == '<' {
+			return true
+		}
+	}
+	return false
+}
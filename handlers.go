@@ -2,14 +2,22 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"html/template"
 	"io"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/iov-one/benchsrv/signclient"
 )
 
 func uploadHandler(store Store, secret string) http.HandlerFunc {
@@ -20,68 +28,273 @@ func uploadHandler(store Store, secret string) http.HandlerFunc {
 			return
 		}
 
-		fd, _, err := r.FormFile("content")
-		defer fd.Close()
-		content, err := ioutil.ReadAll(fd)
+		files, err := collectUploadFiles(r)
 		if err != nil {
 			httpFailf(w, http.StatusBadRequest, "read content: %s", err)
 			return
 		}
-		content = bytes.TrimSpace(content)
-		if len(content) == 0 {
+		if len(files) == 0 {
 			httpFailf(w, http.StatusBadRequest, "content is required")
 			return
 		}
 
 		commit := strings.TrimSpace(r.Form.Get("commit"))
+		title := strings.TrimSpace(r.Form.Get("title"))
+		description := strings.TrimSpace(r.Form.Get("description"))
+
+		payload := signclient.Payload(commit, title, description, signedFiles(files))
+		if sig := r.Header.Get("X-Signature"); !signed(sig, payload, secret) {
+			httpFailf(w, http.StatusUnauthorized, "invalid signature")
+			return
+		}
+
 		if commit == "" {
 			httpFailf(w, http.StatusBadRequest, "commit is required")
 			return
 		}
 
-		if sig := w.Header().Get("signature"); !signed(sig, content, secret) {
-			w.WriteHeader(http.StatusUnauthorized)
+		if len(files) == 1 && files[0].label == "" {
+			content := bytes.TrimSpace(files[0].raw)
+			if len(content) == 0 {
+				httpFailf(w, http.StatusBadRequest, "content is required")
+				return
+			}
+			if len(content) < 10 {
+				// Ignore dummy content.
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			benchID, err := store.CreateBenchmark(r.Context(), string(content), commit, title, description)
+			if err != nil {
+				httpFailf(w, http.StatusInternalServerError, "cannot upload: %s", err)
+				return
+			}
+			indexContent(r.Context(), store, benchID, string(content))
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprintln(w, benchID)
 			return
 		}
 
-		if len(content) < 10 {
-			// Ignore dummy content.
-			w.WriteHeader(http.StatusBadRequest)
-			return
+		runs := make([]BenchmarkInput, 0, len(files))
+		for _, f := range files {
+			content := bytes.TrimSpace(f.raw)
+			if len(content) == 0 {
+				httpFailf(w, http.StatusBadRequest, "content is required")
+				return
+			}
+			runs = append(runs, BenchmarkInput{Label: f.label, Content: string(content)})
 		}
-		benchID, err := store.CreateBenchmark(r.Context(), string(content), commit)
+
+		setID, err := store.CreateBenchmarkSet(r.Context(), commit, title, description, runs)
 		if err != nil {
 			httpFailf(w, http.StatusInternalServerError, "cannot upload: %s", err)
 			return
 		}
+		if set, err := store.FindBenchmarkSet(r.Context(), setID); err == nil {
+			for _, run := range set.Runs {
+				indexContent(r.Context(), store, run.ID, run.Content)
+			}
+		}
 		w.WriteHeader(http.StatusCreated)
-		fmt.Fprintln(w, benchID)
+		fmt.Fprintln(w, setID)
+	}
+}
+
+// indexContent parses content as benchfmt and stores the resulting
+// records against benchmarkID so they can be found via searchHandler.
+// Indexing is best-effort: content that doesn't parse as benchfmt is
+// still retrievable verbatim via showBenchmark's ?raw=1 flag.
+func indexContent(ctx context.Context, store Store, benchmarkID int64, content string) {
+	records, err := parseRecords(content)
+	if err != nil {
+		return
+	}
+	store.CreateBenchmarkRecords(ctx, benchmarkID, records)
+}
+
+// uploadFile is one file part of a multipart upload, together with its
+// optional label.
+type uploadFile struct {
+	label string
+	raw   []byte
+}
+
+// collectUploadFiles gathers every benchmark file part of a multipart
+// upload: the legacy single "content" field, the numbered "content",
+// "content2", "content3", … fields paired with "label", "label2",
+// "label3", …, and the repeated "files[]" field paired positionally
+// with "labels[]".
+func collectUploadFiles(r *http.Request) ([]uploadFile, error) {
+	var files []uploadFile
+
+	for i := 1; ; i++ {
+		key, labelKey := "content", "label"
+		if i > 1 {
+			key = fmt.Sprintf("content%d", i)
+			labelKey = fmt.Sprintf("label%d", i)
+		}
+		fhs := r.MultipartForm.File[key]
+		if len(fhs) == 0 {
+			break
+		}
+		raw, err := readFormFile(fhs[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		files = append(files, uploadFile{
+			label: strings.TrimSpace(r.Form.Get(labelKey)),
+			raw:   raw,
+		})
+	}
+
+	fhs := r.MultipartForm.File["files[]"]
+	labels := r.MultipartForm.Value["labels[]"]
+	for i, fh := range fhs {
+		raw, err := readFormFile(fh)
+		if err != nil {
+			return nil, fmt.Errorf("files[]: %w", err)
+		}
+		var label string
+		if i < len(labels) {
+			label = strings.TrimSpace(labels[i])
+		}
+		files = append(files, uploadFile{label: label, raw: raw})
 	}
+
+	return files, nil
 }
 
+func readFormFile(fh *multipart.FileHeader) ([]byte, error) {
+	f, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ioutil.ReadAll(f)
+}
+
+// signedFiles converts files into the signclient.File slice used to
+// build the signed payload, preserving upload order.
+func signedFiles(files []uploadFile) []signclient.File {
+	out := make([]signclient.File, len(files))
+	for i, f := range files {
+		out[i] = signclient.File{Label: f.label, Content: f.raw}
+	}
+	return out
+}
+
+// signed reports whether sig is a valid hex- or base64-encoded
+// HMAC-SHA256 of content keyed with secret, as sent by clients in the
+// X-Signature header. content is the canonical payload built by
+// signclient.Payload, covering commit, title, description and every
+// file's label and bytes — not just the raw file content — so a
+// signature can't be replayed with different metadata or a different
+// split of the same bytes across files. It uses hmac.Equal throughout
+// to avoid leaking timing information about the expected signature.
 func signed(sig string, content []byte, secret string) bool {
+	if sig == "" {
+		return false
+	}
 
-	// TODO: check the signature of the content to make sure the signer
-	// knows the secret.
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(content)
+	expected := mac.Sum(nil)
+
+	if decoded, err := hex.DecodeString(sig); err == nil {
+		if hmac.Equal(decoded, expected) {
+			return true
+		}
+	}
+
+	if decoded, err := base64.StdEncoding.DecodeString(sig); err == nil {
+		if hmac.Equal(decoded, expected) {
+			return true
+		}
+	}
 
-	return true
+	return false
 }
 
 func showBenchmark(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
 		benchID, _ := strconv.ParseInt(lastChunk(r.URL.Path), 10, 64)
 		if benchID == 0 {
 			httpFailf(w, http.StatusNotFound, "benchmark not found")
 			return
 		}
 
-		switch bench, err := store.FindBenchmark(r.Context(), benchID); err {
+		bench, err := store.FindBenchmark(ctx, benchID)
+		switch err {
 		case nil:
-			io.WriteString(w, bench.Content)
 		case ErrNotFound:
 			httpFailf(w, http.StatusNotFound, "benchmark not found")
+			return
 		default:
 			httpFailf(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		if r.URL.Query().Get("raw") == "1" {
+			io.WriteString(w, bench.Content)
+			return
+		}
+
+		records, err := store.RecordsForBenchmark(ctx, benchID)
+		if err != nil {
+			httpFailf(w, http.StatusInternalServerError, "%s", err)
+			return
+		}
+		if len(records) == 0 {
+			// Nothing was indexed for this benchmark (e.g. it
+			// predates indexing, or didn't parse as benchfmt) —
+			// fall back to the raw content.
+			io.WriteString(w, bench.Content)
+			return
+		}
+		io.WriteString(w, formatRecords(records))
+	}
+}
+
+// searchHandler handles GET /search?q=key:value key2:value2, returning
+// every indexed record whose labels match all of the given key:value
+// pairs.
+func searchHandler(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseSearchQuery(r.URL.Query().Get("q"))
+		if err != nil {
+			httpFailf(w, http.StatusBadRequest, "invalid query: %s", err)
+			return
+		}
+
+		records, err := store.SearchRecords(r.Context(), filter)
+		if err != nil {
+			httpFailf(w, http.StatusInternalServerError, "cannot search: %s", err)
+			return
+		}
+
+		tmpl.ExecuteTemplate(w, "search", records)
+	}
+}
+
+// showBenchmarkSet handles GET /sets/{id}, rendering the runs that make
+// up a benchmark set.
+func showBenchmarkSet(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setID, _ := strconv.ParseInt(lastChunk(r.URL.Path), 10, 64)
+		if setID == 0 {
+			httpFailf(w, http.StatusNotFound, "benchmark set not found")
+			return
+		}
+
+		switch set, err := store.FindBenchmarkSet(r.Context(), setID); err {
+		case nil:
+			tmpl.ExecuteTemplate(w, "set", set)
+		case ErrNotFound:
+			httpFailf(w, http.StatusNotFound, "benchmark set not found")
+		default:
+			httpFailf(w, http.StatusInternalServerError, "%s", err)
 		}
 	}
 }
@@ -98,25 +311,96 @@ func lastChunk(path string) string {
 	return path
 }
 
+const (
+	defaultPerPage = 100
+	maxPerPage     = 500
+	// maxPage bounds the requested page number so that (page-1)*perPage
+	// can't overflow int and turn into a negative Offset.
+	maxPage = 1 << 20
+)
+
 func listHandler(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		benchmarks, err := store.ListBenchmarks(r.Context(), time.Now(), 100)
+		ctx := r.Context()
+		query := r.URL.Query()
+
+		page, _ := strconv.Atoi(query.Get("page"))
+		if page < 1 {
+			page = 1
+		} else if page > maxPage {
+			page = maxPage
+		}
+
+		perPage, _ := strconv.Atoi(query.Get("per_page"))
+		if perPage < 1 {
+			perPage = defaultPerPage
+		} else if perPage > maxPerPage {
+			perPage = maxPerPage
+		}
+
+		filter := ListFilter{
+			Before: time.Now(),
+			Limit:  perPage,
+			Offset: (page - 1) * perPage,
+			Query:  strings.TrimSpace(query.Get("query")),
+		}
+
+		benchmarks, err := store.ListBenchmarks(ctx, filter)
 		if err != nil {
 			httpFailf(w, http.StatusInternalServerError, "cannot list benchmarks: %s", err)
 			return
 		}
-		tmpl.ExecuteTemplate(w, "listing", benchmarks)
+
+		total, err := store.CountBenchmarks(ctx, filter)
+		if err != nil {
+			httpFailf(w, http.StatusInternalServerError, "cannot count benchmarks: %s", err)
+			return
+		}
+
+		tmpl.ExecuteTemplate(w, "listing", listingData{
+			Benchmarks: benchmarks,
+			Query:      filter.Query,
+			Page:       page,
+			PerPage:    perPage,
+			Total:      total,
+			HasNext:    page*perPage < total,
+		})
 	}
 }
 
+// listingData is the view model passed to the "listing" template.
+type listingData struct {
+	Benchmarks []Benchmark
+	Query      string
+	Page       int
+	PerPage    int
+	Total      int
+	HasNext    bool
+}
+
 func compareHandler(store Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
 		query := r.URL.Query()
 
+		if query.Get("set") != "" {
+			setID, _ := strconv.ParseInt(query.Get("set"), 10, 64)
+			set, err := store.FindBenchmarkSet(ctx, setID)
+			if err != nil {
+				code := http.StatusInternalServerError
+				if err == ErrNotFound {
+					code = http.StatusNotFound
+				}
+				httpFailf(w, code, "cannot find benchmark set %d: %s", setID, err)
+				return
+			}
+			writeCompare(w, r, func() ([]byte, error) { return CompareSetText(set) }, func() ([]byte, error) { return CompareSetHTML(set) })
+			return
+		}
+
 		if query.Get("a") == "" || query.Get("b") == "" {
-			httpFailf(w, http.StatusBadRequest, "Missing benchmarks IDs. Usage %s?a=<ID>&b=<ID>", r.URL.Path)
+			httpFailf(w, http.StatusBadRequest, "Missing benchmarks IDs. Usage %s?a=<ID>&b=<ID> or %s?set=<ID>", r.URL.Path, r.URL.Path)
 			return
 		}
 
@@ -142,14 +426,47 @@ func compareHandler(store Store) http.HandlerFunc {
 			return
 		}
 
-		cmp, err := Compare(a, b)
+		writeCompare(w, r, func() ([]byte, error) { return CompareText(a, b) }, func() ([]byte, error) { return CompareHTML(a, b) })
+	}
+}
+
+// writeCompare writes the result of text or html, whichever wantsText
+// picks for r, to w.
+func writeCompare(w http.ResponseWriter, r *http.Request, text, html func() ([]byte, error)) {
+	if wantsText(r) {
+		cmp, err := text()
 		if err != nil {
 			httpFailf(w, http.StatusInternalServerError, "cannot compare: %s", err)
 			return
 		}
-
+		w.Header().Set("content-type", "text/plain;charset=utf-8")
 		w.Write(cmp)
+		return
+	}
+
+	cmp, err := html()
+	if err != nil {
+		httpFailf(w, http.StatusInternalServerError, "cannot compare: %s", err)
+		return
+	}
+	w.Write(cmp)
+}
+
+// wantsText reports whether the client asked for the plain-text
+// benchstat table rather than the default HTML page, either via a
+// text/plain Accept header or the X-Benchsave: 1 fallback used by
+// clients that don't bother with content negotiation.
+func wantsText(r *http.Request) bool {
+	if r.Header.Get("X-Benchsave") == "1" {
+		return true
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+		if mediaType == "text/plain" {
+			return true
+		}
 	}
+	return false
 }
 
 func httpFailf(w http.ResponseWriter, code int, format string, args ...interface{}) {
@@ -159,7 +476,10 @@ func httpFailf(w http.ResponseWriter, code int, format string, args ...interface
 	tmpl.ExecuteTemplate(w, "error", msg)
 }
 
-var tmpl = template.Must(template.New("").Parse(`
+var tmpl = template.Must(template.New("").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+	"dec": func(i int) int { return i - 1 },
+}).Parse(`
 
 {{define "header"}}
 <!doctype html>
@@ -184,7 +504,12 @@ var tmpl = template.Must(template.New("").Parse(`
 
 {{define "listing"}}
 	{{template "header" .}}
-	{{if .}}
+	<form action="/" method="GET">
+		<input type="text" name="query" placeholder="search commit, title, description" value="{{.Query}}">
+		<button type="submit">Search</button>
+	</form>
+	<p>{{.Total}} benchmark{{if ne .Total 1}}s{{end}}</p>
+	{{if .Benchmarks}}
 		<form action="/compare/" method="GET">
 			<table>
 			<thead>
@@ -193,9 +518,10 @@ var tmpl = template.Must(template.New("").Parse(`
 					<td>Compare</td>
 					<td>Created</td>
 					<td>Commit</td>
+					<td>Title</td>
 				</tr>
 			</thead>
-			{{range .}}
+			{{range .Benchmarks}}
 				<tbody>
 					<tr>
 						<td>
@@ -209,14 +535,75 @@ var tmpl = template.Must(template.New("").Parse(`
 						</td>
 						<td>{{.Created.Format "Mon, 2 Jan 15:04"}}</td>
 						<td>{{.Commit}}</td>
+						<td>{{.Title}}</td>
 					</tr>
 				</tbody>
 			{{end}}
 			</table>
 			<button type="submit">Compare</button>
 		</form>
+		<nav>
+			{{if gt .Page 1}}
+				<a href="?page={{dec .Page}}&per_page={{.PerPage}}&query={{.Query}}">&laquo; prev</a>
+			{{end}}
+			{{if .HasNext}}
+				<a href="?page={{inc .Page}}&per_page={{.PerPage}}&query={{.Query}}">next &raquo;</a>
+			{{end}}
+		</nav>
 	{{else}}
 		<div class="error">No benchmarks.</div>
 	{{end}}
 {{end}}
+
+{{define "set"}}
+	{{template "header" .}}
+	<h1>Benchmark set #{{.ID}}</h1>
+	<table>
+		<thead>
+			<tr>
+				<td>ID</td>
+				<td>Label</td>
+				<td>Created</td>
+				<td>Commit</td>
+			</tr>
+		</thead>
+		{{range .Runs}}
+			<tbody>
+				<tr>
+					<td><a href="/benchmarks/{{.ID}}">#{{.ID}}</a></td>
+					<td>{{.Label}}</td>
+					<td>{{.Created.Format "Mon, 2 Jan 15:04"}}</td>
+					<td>{{.Commit}}</td>
+				</tr>
+			</tbody>
+		{{end}}
+	</table>
+	<a href="/compare/?set={{.ID}}">Compare runs</a>
+{{end}}
+
+{{define "search"}}
+	{{template "header" .}}
+	{{if .}}
+		<table>
+			<thead>
+				<tr>
+					<td>Benchmark</td>
+					<td>Labels</td>
+					<td>Result</td>
+				</tr>
+			</thead>
+			{{range .}}
+				<tbody>
+					<tr>
+						<td><a href="/benchmarks/{{.BenchmarkID}}">#{{.BenchmarkID}}</a></td>
+						<td>{{range $k, $v := .Labels}}{{$k}}={{$v}} {{end}}</td>
+						<td>{{.Content}}</td>
+					</tr>
+				</tbody>
+			{{end}}
+		</table>
+	{{else}}
+		<div class="error">No matching results.</div>
+	{{end}}
+{{end}}
 `))
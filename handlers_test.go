@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/iov-one/benchsrv/signclient"
+)
+
+func TestSigned(t *testing.T) {
+	const secret = "shh-its-a-secret"
+	files := []signclient.File{{Content: []byte("BenchmarkFoo-8   1000000   123 ns/op")}}
+
+	payload := signclient.Payload("abc123", "title", "description", files)
+	sig := signclient.Signature("abc123", "title", "description", files, secret)
+
+	t.Run("valid signature", func(t *testing.T) {
+		if !signed(sig, payload, secret) {
+			t.Fatal("expected a valid signature to be accepted")
+		}
+	})
+
+	t.Run("missing header", func(t *testing.T) {
+		if signed("", payload, secret) {
+			t.Fatal("expected an empty signature header to be rejected")
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		if signed(sig, payload, "not-the-secret") {
+			t.Fatal("expected a signature computed with a different secret to be rejected")
+		}
+	})
+
+	t.Run("tampered body", func(t *testing.T) {
+		tampered := append(append([]byte{}, payload...), '!')
+		if signed(sig, tampered, secret) {
+			t.Fatal("expected a signature over tampered content to be rejected")
+		}
+	})
+
+	t.Run("replayed with a different commit", func(t *testing.T) {
+		replayed := signclient.Payload("xyz789", "title", "description", files)
+		if signed(sig, replayed, secret) {
+			t.Fatal("expected a signature to not carry over to a different commit")
+		}
+	})
+
+	t.Run("replayed with a different title", func(t *testing.T) {
+		replayed := signclient.Payload("abc123", "other title", "description", files)
+		if signed(sig, replayed, secret) {
+			t.Fatal("expected a signature to not carry over to a different title")
+		}
+	})
+
+	t.Run("replayed with bytes re-split across more files", func(t *testing.T) {
+		content := files[0].Content
+		split := []signclient.File{
+			{Content: content[:len(content)/2]},
+			{Content: content[len(content)/2:]},
+		}
+		replayed := signclient.Payload("abc123", "title", "description", split)
+		if signed(sig, replayed, secret) {
+			t.Fatal("expected a signature to not carry over to a different file split")
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newListStore(n int) *memStore {
+	s := newMemStore()
+	for i := 1; i <= n; i++ {
+		s.benchmarks = append(s.benchmarks, Benchmark{
+			ID:      int64(i),
+			Commit:  "commit",
+			Title:   "run",
+			Created: time.Now(),
+		})
+	}
+	s.benchmarks = append(s.benchmarks, Benchmark{
+		ID:      int64(n + 1),
+		Commit:  "deadbeef",
+		Title:   "special benchmark",
+		Created: time.Now(),
+	})
+	s.nextID = int64(n + 2)
+	return s
+}
+
+func TestListHandlerPagination(t *testing.T) {
+	store := newListStore(5)
+	h := listHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/?page=1&per_page=2", nil)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	total, err := store.CountBenchmarks(req.Context(), ListFilter{Before: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 6 {
+		t.Fatalf("total = %d, want 6", total)
+	}
+}
+
+func TestListHandlerHugePageDoesNotPanic(t *testing.T) {
+	store := newListStore(5)
+	h := listHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/?page=9223372036854775807&per_page=500", nil)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestListHandlerQuery(t *testing.T) {
+	store := newListStore(5)
+	h := listHandler(store)
+
+	req := httptest.NewRequest(http.MethodGet, "/?query=special", nil)
+	rec := httptest.NewRecorder()
+	h(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	benchmarks, err := store.ListBenchmarks(req.Context(), ListFilter{Before: time.Now(), Limit: defaultPerPage, Query: "special"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(benchmarks) != 1 || benchmarks[0].Commit != "deadbeef" {
+		t.Fatalf("unexpected results: %+v", benchmarks)
+	}
+}
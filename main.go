@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	secret := flag.String("secret", "", "shared HMAC secret for verifying upload signatures")
+	flag.Parse()
+
+	store := newMemStore()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload", uploadHandler(store, *secret))
+	mux.HandleFunc("/benchmarks/", showBenchmark(store))
+	mux.HandleFunc("/sets/", showBenchmarkSet(store))
+	mux.HandleFunc("/search", searchHandler(store))
+	mux.HandleFunc("/compare/", compareHandler(store))
+	mux.HandleFunc("/", listHandler(store))
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/perf/storage/benchfmt"
+)
+
+// Record is a single parsed benchmark result, decomposed into its
+// labels (goos, goarch, pkg, commit, …) and the raw benchmark line, so
+// that it can be indexed and searched independently of the upload that
+// produced it.
+type Record struct {
+	ID          int64
+	BenchmarkID int64
+	Labels      map[string]string
+	Content     string
+}
+
+// parseRecords decodes content, the raw benchfmt text of a single
+// uploaded benchmark run, into its constituent records.
+func parseRecords(content string) ([]Record, error) {
+	reader := benchfmt.NewReader(strings.NewReader(content))
+
+	var records []Record
+	for reader.Next() {
+		result := reader.Result()
+		labels := make(map[string]string, len(result.Labels))
+		for k, v := range result.Labels {
+			labels[k] = v
+		}
+		records = append(records, Record{Labels: labels, Content: result.Content})
+	}
+	if err := reader.Err(); err != nil {
+		return nil, fmt.Errorf("parse benchfmt: %w", err)
+	}
+	return records, nil
+}
+
+// formatRecords reconstructs the benchfmt text representation of
+// records, emitting a "key: value" label line whenever a key's value
+// changes from the previous record, as benchfmt itself does.
+func formatRecords(records []Record) string {
+	var buf strings.Builder
+	last := map[string]string{}
+	for _, rec := range records {
+		keys := make([]string, 0, len(rec.Labels))
+		for k := range rec.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var removed []string
+		for k, v := range last {
+			if _, ok := rec.Labels[k]; !ok && v != "" {
+				removed = append(removed, k)
+			}
+		}
+		sort.Strings(removed)
+		for _, k := range removed {
+			fmt.Fprintf(&buf, "%s:\n", k)
+		}
+
+		for _, k := range keys {
+			if last[k] != rec.Labels[k] {
+				fmt.Fprintf(&buf, "%s: %s\n", k, rec.Labels[k])
+			}
+		}
+		last = rec.Labels
+
+		buf.WriteString(rec.Content)
+		buf.WriteByte('\n')
+	}
+	return buf.String()
+}
+
+// matchesFilter reports whether rec carries every key:value pair in
+// filter.
+func matchesFilter(rec Record, filter map[string]string) bool {
+	for k, v := range filter {
+		if rec.Labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSearchQuery parses a "key:value key2:value2" search expression,
+// as accepted by GET /search?q=..., into a label filter.
+func parseSearchQuery(q string) (map[string]string, error) {
+	filter := make(map[string]string)
+	for _, token := range strings.Fields(q) {
+		key, value, ok := strings.Cut(token, ":")
+		if !ok {
+			return nil, fmt.Errorf("expected key:value, got %q", token)
+		}
+		filter[key] = value
+	}
+	return filter, nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestShowBenchmarkRaw(t *testing.T) {
+	store := newMemStore()
+	id, err := store.CreateBenchmark(context.Background(), "goos: linux\nBenchmarkFoo-8   1000000   100 ns/op\n", "abc123", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/benchmarks/1?raw=1", nil)
+	rec := httptest.NewRecorder()
+	showBenchmark(store)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "BenchmarkFoo-8") {
+		t.Fatalf("unexpected body: %q", rec.Body.String())
+	}
+	_ = id
+}
+
+func TestSearchHandlerMatchesLabel(t *testing.T) {
+	store := newMemStore()
+	store.CreateBenchmarkRecords(context.Background(), 1, []Record{
+		{Labels: map[string]string{"goos": "linux", "pkg": "foo"}, Content: "BenchmarkFoo-8 1000000 100 ns/op"},
+		{Labels: map[string]string{"goos": "darwin", "pkg": "foo"}, Content: "BenchmarkFoo-8 1000000 110 ns/op"},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/search?q=goos:linux", nil)
+	rec := httptest.NewRecorder()
+	searchHandler(store)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), "100 ns/op") || strings.Contains(rec.Body.String(), "110 ns/op") {
+		t.Fatalf("expected only the linux result, got %q", rec.Body.String())
+	}
+}
+
+func TestParseSearchQueryRejectsMalformedToken(t *testing.T) {
+	if _, err := parseSearchQuery("goos"); err == nil {
+		t.Fatal("expected an error for a token without a colon")
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestShowBenchmarkSet(t *testing.T) {
+	store := newMemStore()
+	setID, err := store.CreateBenchmarkSet(context.Background(), "abc123", "widget regression", "", []BenchmarkInput{
+		{Label: "old", Content: "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		{Label: "new", Content: "BenchmarkFoo-8   1000000   120 ns/op\n"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/sets/1", nil)
+	rec := httptest.NewRecorder()
+	showBenchmarkSet(store)(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !containsTag(rec.Body.String()) {
+		t.Fatalf("expected an HTML page, got %q", rec.Body.String())
+	}
+	_ = setID
+}
+
+func TestShowBenchmarkSetNotFound(t *testing.T) {
+	store := newMemStore()
+
+	req := httptest.NewRequest(http.MethodGet, "/sets/404", nil)
+	rec := httptest.NewRecorder()
+	showBenchmarkSet(store)(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,64 @@
+// Package signclient computes the X-Signature header expected by the
+// benchsrv upload endpoint, so that tools like benchsave can sign their
+// uploads without reimplementing the HMAC scheme themselves.
+package signclient
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"net/http"
+)
+
+// File is one uploaded benchmark file, paired with its optional label,
+// as it will be parsed out of the multipart upload. Order matters: it
+// must match the order the server reassembles files in.
+type File struct {
+	Label   string
+	Content []byte
+}
+
+// Payload deterministically encodes commit, title, description and
+// files into the byte stream that Sign authenticates. Every field is
+// length-prefixed rather than concatenated bare, so that an attacker
+// can't replay a valid signature by changing commit/title/description,
+// relabelling a run, or re-splitting the same file bytes across a
+// different number of parts.
+func Payload(commit, title, description string, files []File) []byte {
+	var buf bytes.Buffer
+	writeField(&buf, []byte(commit))
+	writeField(&buf, []byte(title))
+	writeField(&buf, []byte(description))
+	for _, f := range files {
+		writeField(&buf, []byte(f.Label))
+		writeField(&buf, f.Content)
+	}
+	return buf.Bytes()
+}
+
+// writeField appends b to buf prefixed with its length as an 8-byte
+// big-endian integer, so that variable-length fields can't be confused
+// with one another once concatenated.
+func writeField(buf *bytes.Buffer, b []byte) {
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], uint64(len(b)))
+	buf.Write(length[:])
+	buf.Write(b)
+}
+
+// Sign computes the signature over commit, title, description and
+// files (see Payload) and sets it as the X-Signature header on req.
+func Sign(req *http.Request, commit, title, description string, files []File, secret string) {
+	req.Header.Set("X-Signature", Signature(commit, title, description, files, secret))
+}
+
+// Signature returns the hex-encoded HMAC-SHA256 of the canonical
+// payload built from commit, title, description and files, keyed with
+// secret.
+func Signature(commit, title, description string, files []File, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(Payload(commit, title, description, files))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Store methods when the requested benchmark
+// does not exist.
+var ErrNotFound = errors.New("benchmark not found")
+
+// Benchmark is a single uploaded benchmark run.
+type Benchmark struct {
+	ID          int64
+	Commit      string
+	Title       string
+	Description string
+	Content     string
+	Created     time.Time
+	// SetID is the BenchmarkSet this run belongs to, or 0 if it was
+	// uploaded on its own.
+	SetID int64
+	// Label distinguishes runs within the same set, e.g. "old", "new"
+	// or "goos=linux".
+	Label string
+}
+
+// BenchmarkInput is one run of a multi-benchmark upload, before it has
+// been assigned an ID.
+type BenchmarkInput struct {
+	Label   string
+	Content string
+}
+
+// BenchmarkSet groups the runs of a single multi-benchmark upload, e.g.
+// the full matrix produced by one `go test -bench` CI job.
+type BenchmarkSet struct {
+	ID          int64
+	Commit      string
+	Title       string
+	Description string
+	Created     time.Time
+	Runs        []Benchmark
+}
+
+// ListFilter narrows down which benchmarks ListBenchmarks and
+// CountBenchmarks consider.
+type ListFilter struct {
+	// Before restricts the results to benchmarks created strictly
+	// before this time.
+	Before time.Time
+	// Limit caps the number of results returned by ListBenchmarks. It
+	// is ignored by CountBenchmarks.
+	Limit int
+	// Offset skips this many matching benchmarks before collecting
+	// Limit of them. It is ignored by CountBenchmarks.
+	Offset int
+	// Query, if non-empty, restricts the results to benchmarks whose
+	// Commit, Title or Description contain it, case-insensitively.
+	Query string
+}
+
+// Store persists uploaded benchmarks.
+type Store interface {
+	// CreateBenchmark stores content for commit, with an optional
+	// title and description to search by, and returns the ID it was
+	// assigned.
+	CreateBenchmark(ctx context.Context, content, commit, title, description string) (int64, error)
+	// FindBenchmark returns the benchmark with the given ID, or
+	// ErrNotFound if it does not exist.
+	FindBenchmark(ctx context.Context, id int64) (Benchmark, error)
+	// ListBenchmarks returns the benchmarks matching filter, most
+	// recent first.
+	ListBenchmarks(ctx context.Context, filter ListFilter) ([]Benchmark, error)
+	// CountBenchmarks returns the total number of benchmarks matching
+	// filter, ignoring its Limit and Offset.
+	CountBenchmarks(ctx context.Context, filter ListFilter) (int, error)
+	// CreateBenchmarkSet stores runs as a single benchmark set for
+	// commit, with an optional title and description to search by, and
+	// returns the set's ID.
+	CreateBenchmarkSet(ctx context.Context, commit, title, description string, runs []BenchmarkInput) (int64, error)
+	// FindBenchmarkSet returns the benchmark set with the given ID, or
+	// ErrNotFound if it does not exist.
+	FindBenchmarkSet(ctx context.Context, id int64) (BenchmarkSet, error)
+	// CreateBenchmarkRecords stores the benchfmt records parsed out of
+	// benchmarkID's content, so they can be found again by SearchRecords.
+	CreateBenchmarkRecords(ctx context.Context, benchmarkID int64, records []Record) error
+	// RecordsForBenchmark returns the records previously stored for
+	// benchmarkID, in upload order.
+	RecordsForBenchmark(ctx context.Context, benchmarkID int64) ([]Record, error)
+	// SearchRecords returns every record whose labels match filter,
+	// across every uploaded benchmark.
+	SearchRecords(ctx context.Context, filter map[string]string) ([]Record, error)
+}
+
+// memStore is an in-memory Store implementation, used in tests and as
+// the server's store; nothing it holds outlives the process.
+type memStore struct {
+	mu           sync.Mutex
+	benchmarks   []Benchmark
+	sets         []BenchmarkSet
+	records      []Record
+	nextID       int64
+	nextSetID    int64
+	nextRecordID int64
+}
+
+func newMemStore() *memStore {
+	return &memStore{nextID: 1, nextSetID: 1, nextRecordID: 1}
+}
+
+func (s *memStore) CreateBenchmark(ctx context.Context, content, commit, title, description string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.benchmarks = append(s.benchmarks, Benchmark{
+		ID:          id,
+		Commit:      commit,
+		Title:       title,
+		Description: description,
+		Content:     content,
+		Created:     time.Now(),
+	})
+	return id, nil
+}
+
+func (s *memStore) FindBenchmark(ctx context.Context, id int64) (Benchmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.benchmarks {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return Benchmark{}, ErrNotFound
+}
+
+func (s *memStore) ListBenchmarks(ctx context.Context, filter ListFilter) ([]Benchmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matched := s.filter(filter)
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].Created.After(matched[j].Created)
+	})
+
+	offset := filter.Offset
+	if offset < 0 {
+		offset = 0
+	}
+	if offset >= len(matched) {
+		return nil, nil
+	}
+	matched = matched[offset:]
+	if filter.Limit > 0 && len(matched) > filter.Limit {
+		matched = matched[:filter.Limit]
+	}
+	return matched, nil
+}
+
+func (s *memStore) CountBenchmarks(ctx context.Context, filter ListFilter) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.filter(filter)), nil
+}
+
+func (s *memStore) CreateBenchmarkSet(ctx context.Context, commit, title, description string, inputs []BenchmarkInput) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	setID := s.nextSetID
+	s.nextSetID++
+
+	set := BenchmarkSet{
+		ID:          setID,
+		Commit:      commit,
+		Title:       title,
+		Description: description,
+		Created:     time.Now(),
+	}
+	for _, in := range inputs {
+		b := Benchmark{
+			ID:          s.nextID,
+			Commit:      commit,
+			Title:       title,
+			Description: description,
+			Content:     in.Content,
+			Created:     set.Created,
+			SetID:       setID,
+			Label:       in.Label,
+		}
+		s.nextID++
+		s.benchmarks = append(s.benchmarks, b)
+		set.Runs = append(set.Runs, b)
+	}
+
+	s.sets = append(s.sets, set)
+	return setID, nil
+}
+
+func (s *memStore) FindBenchmarkSet(ctx context.Context, id int64) (BenchmarkSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, set := range s.sets {
+		if set.ID == id {
+			return set, nil
+		}
+	}
+	return BenchmarkSet{}, ErrNotFound
+}
+
+func (s *memStore) CreateBenchmarkRecords(ctx context.Context, benchmarkID int64, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rec := range records {
+		rec.ID = s.nextRecordID
+		rec.BenchmarkID = benchmarkID
+		s.nextRecordID++
+		s.records = append(s.records, rec)
+	}
+	return nil
+}
+
+func (s *memStore) RecordsForBenchmark(ctx context.Context, benchmarkID int64) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []Record
+	for _, rec := range s.records {
+		if rec.BenchmarkID == benchmarkID {
+			matched = append(matched, rec)
+		}
+	}
+	return matched, nil
+}
+
+func (s *memStore) SearchRecords(ctx context.Context, filter map[string]string) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var matched []Record
+	for _, rec := range s.records {
+		if matchesFilter(rec, filter) {
+			matched = append(matched, rec)
+		}
+	}
+	return matched, nil
+}
+
+// filter returns the benchmarks matching filter's Before and Query
+// fields, ignoring Limit and Offset. Callers must hold s.mu.
+func (s *memStore) filter(filter ListFilter) []Benchmark {
+	query := strings.ToLower(filter.Query)
+
+	var matched []Benchmark
+	for _, b := range s.benchmarks {
+		if !filter.Before.IsZero() && !b.Created.Before(filter.Before) {
+			continue
+		}
+		if query != "" && !matchesQuery(b, query) {
+			continue
+		}
+		matched = append(matched, b)
+	}
+	return matched
+}
+
+// matchesQuery reports whether b's Commit, Title or Description contain
+// the already-lowercased query string.
+func matchesQuery(b Benchmark, query string) bool {
+	return strings.Contains(strings.ToLower(b.Commit), query) ||
+		strings.Contains(strings.ToLower(b.Title), query) ||
+		strings.Contains(strings.ToLower(b.Description), query)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/iov-one/benchsrv/signclient"
+)
+
+// multipartUpload builds a multipart/form-data upload request, signed
+// for secret. Each part is a (field name, content) pair. Every entry of
+// labels is written as a "labels[]" field, which the handler pairs
+// positionally with the "files[]" parts. fields is written
+// verbatim, e.g. to set "title"/"description".
+func multipartUpload(t *testing.T, secret, commit string, parts [][2]string, labels []string, fields map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	if err := mw.WriteField("commit", commit); err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range fields {
+		if err := mw.WriteField(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, label := range labels {
+		if err := mw.WriteField("labels[]", label); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var files []signclient.File
+	filesIdx := 0
+	for _, part := range parts {
+		field, content := part[0], part[1]
+		fw, err := mw.CreateFormFile(field, field+".txt")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+
+		var label string
+		if field == "files[]" {
+			if filesIdx < len(labels) {
+				label = labels[filesIdx]
+			}
+			filesIdx++
+		}
+		files = append(files, signclient.File{Label: label, Content: []byte(content)})
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	signclient.Sign(req, commit, fields["title"], fields["description"], files, secret)
+	return req
+}
+
+func TestUploadHandlerSingleBenchmark(t *testing.T) {
+	const secret = "shh"
+	store := newMemStore()
+
+	req := multipartUpload(t, secret, "abc123", [][2]string{{"content", "BenchmarkFoo-8   1000000   100 ns/op\n"}}, nil, nil)
+
+	rec := httptest.NewRecorder()
+	uploadHandler(store, secret)(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(store.benchmarks) != 1 {
+		t.Fatalf("expected a single benchmark, got %d", len(store.benchmarks))
+	}
+	if len(store.sets) != 0 {
+		t.Fatalf("expected no benchmark sets, got %d", len(store.sets))
+	}
+}
+
+func TestUploadHandlerBenchmarkSet(t *testing.T) {
+	const secret = "shh"
+	store := newMemStore()
+
+	req := multipartUpload(t, secret, "abc123", [][2]string{
+		{"files[]", "BenchmarkFoo-8   1000000   100 ns/op\n"},
+		{"files[]", "BenchmarkFoo-8   1000000   120 ns/op\n"},
+	}, []string{"old", "new"}, nil)
+
+	rec := httptest.NewRecorder()
+	uploadHandler(store, secret)(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if len(store.sets) != 1 {
+		t.Fatalf("expected a single benchmark set, got %d", len(store.sets))
+	}
+	set := store.sets[0]
+	if len(set.Runs) != 2 {
+		t.Fatalf("expected 2 runs in the set, got %d", len(set.Runs))
+	}
+	if set.Runs[0].Label != "old" || set.Runs[1].Label != "new" {
+		t.Fatalf("unexpected labels: %+v", set.Runs)
+	}
+}
+
+func TestUploadHandlerRejectsReplayedSignatureWithDifferentCommit(t *testing.T) {
+	const secret = "shh"
+	store := newMemStore()
+
+	req := multipartUpload(t, secret, "abc123", [][2]string{{"content", "BenchmarkFoo-8   1000000   100 ns/op\n"}}, nil, nil)
+
+	// Reuse a validly-signed request's signature with a different commit.
+	// Pre-seeding Form makes ParseMultipartForm merge into it instead of
+	// overwriting it, so the handler sees "evil000" as r.Form.Get("commit").
+	req.Form = url.Values{"commit": {"evil000"}}
+
+	rec := httptest.NewRecorder()
+	uploadHandler(store, secret)(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, body = %s, want %d", rec.Code, rec.Body.String(), http.StatusUnauthorized)
+	}
+}
+
+func TestUploadHandlerPersistsTitleAndDescription(t *testing.T) {
+	const secret = "shh"
+	store := newMemStore()
+
+	req := multipartUpload(t, secret, "abc123", [][2]string{{"content", "BenchmarkFoo-8   1000000   100 ns/op\n"}}, nil, map[string]string{
+		"title":       "widget regression",
+		"description": "checks the widget allocator hot path",
+	})
+
+	rec := httptest.NewRecorder()
+	uploadHandler(store, secret)(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	benchmarks, err := store.ListBenchmarks(req.Context(), ListFilter{Limit: defaultPerPage, Query: "widget"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(benchmarks) != 1 {
+		t.Fatalf("expected the upload to be searchable by title, got %d matches", len(benchmarks))
+	}
+	if benchmarks[0].Description != "checks the widget allocator hot path" {
+		t.Fatalf("description = %q", benchmarks[0].Description)
+	}
+}